@@ -0,0 +1,243 @@
+//
+// Description: Operator authentication - scrypt-hashed passwords, JWT
+// sessions and role-based middleware for the management routes
+// Sources:
+// https://github.com/dgrijalva/jwt-go
+// https://pkg.go.dev/golang.org/x/crypto/scrypt
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/dgrijalva/jwt-go"
+	"github.com/gin-gonic/gin"
+	"golang.org/x/crypto/scrypt"
+)
+
+// user mirrors a row in the users table.
+type user struct {
+	Username string `json:"username"`
+	Role     string `json:"role"`
+}
+
+// loginRequest is the expected JSON body of POST /auth/login.
+type loginRequest struct {
+	Username string `json:"username"`
+	Password string `json:"password"`
+}
+
+// claims is the set of fields stored in a gatekeeper JWT.
+type claims struct {
+	jwt.StandardClaims
+	Role string `json:"role"`
+}
+
+// authAPI holds the dependencies shared by the /auth routes and RequireRole,
+// built once in main() instead of every handler opening its own database
+// connection.
+type authAPI struct {
+	repo *userRepo
+}
+
+// generateSalt returns a fresh random salt for hashing a new password,
+// the same way the burgerauth project derives one from crypto/rand.
+func generateSalt() ([]byte, error) {
+	salt := make([]byte, 16)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	return salt, nil
+}
+
+// hashPassword hashes password with the given salt using scrypt.
+func hashPassword(password string, salt []byte) ([]byte, error) {
+	return scrypt.Key([]byte(password), salt, 32768, 8, 1, 32)
+}
+
+// This function gets called by the API
+// POST to /auth/login
+//
+// Example request body:
+//
+//	{
+//		"username": "jordy",
+//		"password": "hunter2"
+//	}
+func (a *authAPI) loginHandler(c *gin.Context) {
+	var req loginRequest
+	if err := c.BindJSON(&req); err != nil {
+		return
+	}
+
+	if req.Username == "" || req.Password == "" {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Username and password are required"})
+		return
+	}
+
+	stored, role, err := a.repo.GetCredentials(c.Request.Context(), req.Username)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid username or password"})
+		return
+	}
+
+	hashed, err := hashPassword(req.Password, stored.salt)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	if subtle.ConstantTimeCompare(hashed, stored.hash) != 1 {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid username or password"})
+		return
+	}
+
+	token, err := signToken(req.Username, role, time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// This function gets called by the API
+// POST to /auth/refresh
+//
+// Expects the Authorization header to carry a still-valid token and returns
+// a new one with a fresh expiry, so clients don't have to re-send a password
+// just to stay logged in.
+func (a *authAPI) refreshHandler(c *gin.Context) {
+	parsed, err := parseBearerToken(c)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+		return
+	}
+
+	revoked, err := a.repo.IsTokenRevoked(c.Request.Context(), c.GetHeader("Authorization"))
+	if err != nil {
+		logger.Error().Err(err).Msg("Cannot check token revocation")
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	if revoked {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+		return
+	}
+
+	token, err := signToken(parsed.Subject, parsed.Role, time.Hour)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"token": token})
+}
+
+// This function gets called by the API
+// POST to /auth/logout
+//
+// Revokes the bearer token used for the request so it can no longer be
+// used to authenticate, even if it has not expired yet.
+func (a *authAPI) logoutHandler(c *gin.Context) {
+	rawToken := c.GetHeader("Authorization")
+	if _, err := parseBearerToken(c); err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+		return
+	}
+
+	if err := a.repo.RevokeToken(c.Request.Context(), rawToken); err != nil {
+		logger.Error().Err(err).Msg("Cannot revoke token")
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"message": "Logged out"})
+}
+
+// signToken creates and signs a JWT for the given username and role, valid
+// for the given duration, using config.Auth.Secret.
+func signToken(username, role string, validFor time.Duration) (string, error) {
+	now := time.Now()
+	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims{
+		StandardClaims: jwt.StandardClaims{
+			Subject:   username,
+			ExpiresAt: now.Add(validFor).Unix(),
+			IssuedAt:  now.Unix(),
+		},
+		Role: role,
+	})
+	return token.SignedString([]byte(config.Auth.Secret))
+}
+
+// parseBearerToken extracts and validates the JWT from the request's
+// Authorization header, returning the parsed claims.
+func parseBearerToken(c *gin.Context) (*claims, error) {
+	header := c.GetHeader("Authorization")
+	rawToken := strings.TrimPrefix(header, "Bearer ")
+	if rawToken == header {
+		return nil, jwt.NewValidationError("missing bearer prefix", jwt.ValidationErrorMalformed)
+	}
+
+	parsed, err := jwt.ParseWithClaims(rawToken, &claims{}, func(t *jwt.Token) (interface{}, error) {
+		// Reject anything but HMAC so a token signed with "none" or a
+		// different algorithm can't trick us into validating against our
+		// own secret (the dgrijalva/jwt-go confused-signing-method footgun).
+		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
+			return nil, jwt.NewValidationError("unexpected signing method", jwt.ValidationErrorSignatureInvalid)
+		}
+		return []byte(config.Auth.Secret), nil
+	})
+	if err != nil || !parsed.Valid {
+		return nil, err
+	}
+	return parsed.Claims.(*claims), nil
+}
+
+// roleRank orders roles from least to most privileged. A token satisfies
+// RequireRole(required) if its own role ranks at or above required, so an
+// admin token (who can create/delete visitors) can also reach
+// operator-gated routes like scanning and listing.
+var roleRank = map[string]int{
+	"operator": 1,
+	"admin":    2,
+}
+
+// RequireRole returns a Gin middleware that rejects any request whose bearer
+// token is missing, expired, revoked, or does not carry at least the
+// required role. It reuses a's shared userRepo instead of opening a fresh
+// database connection on every gated request.
+func (a *authAPI) RequireRole(role string) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		parsed, err := parseBearerToken(c)
+		if err != nil {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+			return
+		}
+
+		if roleRank[parsed.Role] < roleRank[role] {
+			c.AbortWithStatusJSON(http.StatusForbidden, gin.H{"message": "Insufficient role"})
+			return
+		}
+
+		revoked, err := a.repo.IsTokenRevoked(c.Request.Context(), c.GetHeader("Authorization"))
+		if err != nil {
+			logger.Error().Err(err).Msg("Cannot check token revocation")
+			c.AbortWithStatusJSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+		if revoked {
+			c.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"message": "Invalid or expired token"})
+			return
+		}
+
+		c.Set("username", parsed.Subject)
+		c.Set("role", parsed.Role)
+		c.Next()
+	}
+}
@@ -0,0 +1,44 @@
+//
+// Description: HTTP routes letting operators trigger the gate remotely,
+// publishing the same MQTT commands the physical controller listens for
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"net/http"
+
+	"github.com/gin-gonic/gin"
+)
+
+// This function gets called by the API
+// POST to /barrier/open
+func openBarrier(c *gin.Context) {
+	if broker == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "MQTT broker not connected"})
+		return
+	}
+	if err := broker.PublishBarrierCommand("open"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	logger.Info().Msg("Barrier open command sent")
+	c.JSON(http.StatusOK, gin.H{"message": "Open command sent"})
+}
+
+// This function gets called by the API
+// POST to /barrier/close
+func closeBarrier(c *gin.Context) {
+	if broker == nil {
+		c.JSON(http.StatusServiceUnavailable, gin.H{"message": "MQTT broker not connected"})
+		return
+	}
+	if err := broker.PublishBarrierCommand("close"); err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	logger.Info().Msg("Barrier close command sent")
+	c.JSON(http.StatusOK, gin.H{"message": "Close command sent"})
+}
@@ -0,0 +1,136 @@
+//
+// Description: MQTT integration for the physical barrier controller -
+// publishes scan decisions and accepts remote open/close commands
+// Sources:
+// https://pkg.go.dev/github.com/eclipse/paho.mqtt.golang
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	mqtt "github.com/eclipse/paho.mqtt.golang"
+)
+
+// broker is the shared MQTT connection used by scanPlate, scanVisitorImage
+// and the /barrier/{open,close} routes. It stays nil when the `mqtt:`
+// config section could not be connected to, in which case barrier commands
+// and scan events are simply skipped.
+var broker *Broker
+
+// Broker wraps a connected MQTT client with the topics this gate uses.
+// repo is the shared VisitorRepo, reused to record barrier events instead
+// of opening a fresh connection per MQTT message.
+type Broker struct {
+	client mqtt.Client
+	repo   *VisitorRepo
+}
+
+// scanEventPayload is published to gatekeeper/scan/{allowed|denied} for
+// every scan decision, whether it came from the console or the HTTP API.
+type scanEventPayload struct {
+	Plate     string    `json:"plate"`
+	Name      string    `json:"name"`
+	Timestamp time.Time `json:"timestamp"`
+	Source    string    `json:"source"`
+}
+
+// barrierCommandPayload is published to the configured barrier command
+// topic to tell the physical controller to open or close the gate.
+type barrierCommandPayload struct {
+	Action string `json:"action"`
+}
+
+// barrierStatusPayload is the shape of messages the barrier controller
+// pushes back on gatekeeper/barrier/status.
+type barrierStatusPayload struct {
+	Status    string    `json:"status"`
+	Timestamp time.Time `json:"timestamp"`
+}
+
+// NewBroker connects to the MQTT broker configured under the `mqtt:` YAML
+// section and subscribes to the barrier status topic. repo is the shared
+// VisitorRepo built at startup, used to record barrier_events.
+func NewBroker(repo *VisitorRepo) (*Broker, error) {
+	scheme := "tcp"
+	if config.MQTT.TLS {
+		scheme = "ssl"
+	}
+
+	opts := mqtt.NewClientOptions()
+	opts.AddBroker(fmt.Sprintf("%s://%s:%d", scheme, config.MQTT.Host, config.MQTT.Port))
+	opts.SetClientID(config.MQTT.ClientID)
+	opts.SetUsername(config.MQTT.Username)
+	opts.SetPassword(config.MQTT.Password)
+
+	client := mqtt.NewClient(opts)
+	if token := client.Connect(); token.Wait() && token.Error() != nil {
+		return nil, token.Error()
+	}
+
+	b := &Broker{client: client, repo: repo}
+	b.subscribeBarrierStatus()
+	return b, nil
+}
+
+// PublishScanDecision publishes a scan decision to gatekeeper/scan/allowed
+// or gatekeeper/scan/denied, depending on decision.
+func (b *Broker) PublishScanDecision(plate, name, decision, source string) {
+	topic := config.MQTT.Topics.ScanDenied
+	if decision == "allowed" {
+		topic = config.MQTT.Topics.ScanAllowed
+	}
+
+	data, err := json.Marshal(scanEventPayload{
+		Plate:     plate,
+		Name:      name,
+		Timestamp: time.Now(),
+		Source:    source,
+	})
+	if err != nil {
+		logger.Error().Err(err).Msg("Cannot marshal scan event")
+		return
+	}
+
+	token := b.client.Publish(topic, 0, false, data)
+	token.Wait()
+	if err := token.Error(); err != nil {
+		logger.Error().Err(err).Msg("Cannot publish scan event")
+	}
+}
+
+// PublishBarrierCommand publishes an "open" or "close" command to the
+// configured barrier command topic.
+func (b *Broker) PublishBarrierCommand(action string) error {
+	data, err := json.Marshal(barrierCommandPayload{Action: action})
+	if err != nil {
+		return err
+	}
+
+	token := b.client.Publish(config.MQTT.Topics.BarrierCommand, 0, false, data)
+	token.Wait()
+	return token.Error()
+}
+
+// subscribeBarrierStatus subscribes to the barrier status topic so the
+// physical controller can push open/closed/error events back into
+// trace.log and the barrier_events table.
+func (b *Broker) subscribeBarrierStatus() {
+	b.client.Subscribe(config.MQTT.Topics.BarrierStatus, 0, func(_ mqtt.Client, msg mqtt.Message) {
+		var status barrierStatusPayload
+		if err := json.Unmarshal(msg.Payload(), &status); err != nil {
+			logger.Error().Err(err).Msg("Cannot parse barrier status")
+			return
+		}
+		logger.Info().Str("status", status.Status).Msg("Barrier status")
+		if err := b.repo.InsertBarrierEvent(context.Background(), status); err != nil {
+			logger.Error().Err(err).Msg("Cannot record barrier event")
+		}
+	})
+}
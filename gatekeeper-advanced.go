@@ -16,10 +16,9 @@ package main
 
 import (
 	"bufio"
+	"context"
 	"database/sql"
 	"fmt"
-	"io"
-	"log"
 	"net/http"
 	"os"
 	"time"
@@ -28,55 +27,123 @@ import (
 	"gopkg.in/yaml.v3"
 )
 
-// Function used to log debug information when debug mode is enabled
-func debug(s string) {
-	if config.Global.Debug {
-		log.Println("DEBUG: " + s)
-	}
-}
-
 // Init function to connect to load config and connect to db
 func init() {
 	// Create a new log file or open the existing log file in append mode (add new lines)
 	logFile, err := os.OpenFile("trace.log", os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0666)
 	if err != nil {
-		log.Fatalf("Couldn't create logfile")
+		panic("Couldn't create logfile")
 	}
 
-	writer := io.MultiWriter(os.Stdout, logFile)
-	log.SetOutput(writer)
-
 	// Read the config file
 	readYaml()
 
+	if config.Auth.Secret == "" {
+		panic("config.yaml: auth.secret is empty. Set it to a random secret before starting the server, or every JWT can be forged.")
+	}
+
+	initLogger(logFile)
+
+	// Pick the OCR backend based on the `ocr:` config section: a remote
+	// recognition service if one is configured, otherwise the local
+	// openalpr binary.
+	if config.OCR.Endpoint != "" {
+		ocrProvider = HTTPOCRProvider{
+			Endpoint: config.OCR.Endpoint,
+			APIKey:   config.OCR.APIKey,
+			Timeout:  time.Duration(config.OCR.TimeoutSec) * time.Second,
+			Retries:  config.OCR.Retries,
+		}
+	}
+
 	// Check DB connection
 	db, err := initializeDB()
 	if err != nil {
-		log.Printf("WARN: Cannot connect to the database: %v", err)
+		logger.Warn().Err(err).Msg("Cannot connect to the database")
 	}
 	defer db.Close()
 }
 
 // Create a struct to store the visitors
 // Used to convert the data to JSON
+//
+// ValidFrom, ValidUntil and MaxEntries are optional, for booking-style
+// access: a nil ValidFrom/ValidUntil means the plate is valid
+// immediately/indefinitely, and a nil MaxEntries means unlimited entries.
+// EntriesUsed counts down from MaxEntries as the plate is scanned, and the
+// gate denies access once it reaches 0.
 type visitors struct {
-	Name  string `json:"name"`
-	Plate string `json:"plate"`
+	Name        string     `json:"name"`
+	Plate       string     `json:"plate"`
+	ValidFrom   *time.Time `json:"valid_from,omitempty"`
+	ValidUntil  *time.Time `json:"valid_until,omitempty"`
+	MaxEntries  *int       `json:"max_entries,omitempty"`
+	EntriesUsed *int       `json:"entries_used,omitempty"`
 }
 
 func main() {
 	// Create a new instance of the gin router
 	router := gin.Default()
+	router.Use(metricsMiddleware())
+
+	// Open the database connection once at startup and share it across
+	// requests, instead of every handler calling initializeDB() itself.
+	db, err := initializeDB()
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot connect to the database")
+	}
+	defer db.Close()
+
+	api := &visitorAPI{repo: NewVisitorRepo(db)}
+	auth := &authAPI{repo: newUserRepo(db)}
+
+	// Connect to the barrier hardware broker. A failed connection is not
+	// fatal: scans keep working, they just won't publish MQTT events and
+	// /barrier/{open,close} will report the broker as unavailable.
+	if b, err := NewBroker(api.repo); err != nil {
+		logger.Warn().Err(err).Msg("Cannot connect to MQTT broker")
+	} else {
+		broker = b
+	}
+
+	// Auth routes are unauthenticated by design, as are /metrics and /ping;
+	// everything else now requires a valid session.
+	router.POST("/auth/login", auth.loginHandler)
+	router.POST("/auth/refresh", auth.refreshHandler)
+	router.POST("/auth/logout", auth.logoutHandler)
 
 	// Create a new route for the APIs
-	router.GET("/visitors", getVisitors)
-	router.GET("/visitors/:plate", getVisitors)
-	router.POST("/visitors", addVisitor)
-	router.DELETE("/visitors/:plate", removeVisitor)
+	router.GET("/visitors", auth.RequireRole("operator"), api.getVisitors)
+	router.GET("/visitors/:plate", auth.RequireRole("operator"), api.getVisitors)
+	router.POST("/visitors", auth.RequireRole("admin"), api.addVisitor)
+	router.DELETE("/visitors/:plate", auth.RequireRole("admin"), api.removeVisitor)
+	router.GET("/visitors/history", auth.RequireRole("operator"), api.getVisitorHistory)
+	router.POST("/visitors/scan", auth.RequireRole("operator"), api.scanVisitorImage)
+	router.POST("/barrier/open", auth.RequireRole("operator"), openBarrier)
+	router.POST("/barrier/close", auth.RequireRole("operator"), closeBarrier)
+	router.GET("/metrics", metricsHandler)
 	router.GET("/ping", func(c *gin.Context) {
 		c.JSON(http.StatusOK, gin.H{"message": "pong"})
 	})
 
+	// Sweep expired/exhausted visitors into visitors_history every minute.
+	// checkScannedPlateInDB still checks the booking window and remaining
+	// entries itself, so a row the sweep has not moved yet is still denied.
+	go func() {
+		ticker := time.NewTicker(time.Minute)
+		defer ticker.Stop()
+		for range ticker.C {
+			n, err := api.repo.SweepExpired(context.Background())
+			if err != nil {
+				logger.Error().Err(err).Msg("Cannot sweep expired visitors")
+				continue
+			}
+			if n > 0 {
+				logger.Info().Int64("count", n).Msg("Swept expired visitors to history")
+			}
+		}
+	}()
+
 	// Run the server
 	router.Run("0.0.0.0:8080")
 
@@ -101,16 +168,36 @@ func main() {
 //			"plate": "DEF-456"
 //		}
 //	]
-func getVisitors(c *gin.Context) {
+func (a *visitorAPI) getVisitors(c *gin.Context) {
 	// Get the plate from the URL
 	// Example: /visitors/ABC-123
 	plate := c.Param("plate")
-	results := getVisitorsFromDB(plate)
-	if results == nil {
+	ctx := c.Request.Context()
+
+	if plate == "" {
+		results, err := a.repo.List(ctx)
+		if err != nil {
+			c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+			return
+		}
+		if results == nil {
+			c.JSON(http.StatusNotFound, gin.H{"message": "Plate is not found in the database"})
+			return
+		}
+		c.JSON(http.StatusOK, results)
+		return
+	}
+
+	visitor, err := a.repo.Get(ctx, plate)
+	if err == sql.ErrNoRows {
 		c.JSON(http.StatusNotFound, gin.H{"message": "Plate is not found in the database"})
 		return
 	}
-	c.JSON(http.StatusOK, results)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	c.JSON(http.StatusOK, []visitors{visitor})
 }
 
 // This function gets called by the API
@@ -120,9 +207,12 @@ func getVisitors(c *gin.Context) {
 //
 //	{
 //		"name": "Jordy",
-//		"plate": "ABC-123"
+//		"plate": "ABC-123",
+//		"valid_from": "2026-07-01T00:00:00Z",
+//		"valid_until": "2026-07-14T00:00:00Z",
+//		"max_entries": 10
 //	}
-func addVisitor(c *gin.Context) {
+func (a *visitorAPI) addVisitor(c *gin.Context) {
 
 	// Bind the JSON data to the newVisitor struct
 	var newVisitor visitors
@@ -136,143 +226,77 @@ func addVisitor(c *gin.Context) {
 		return
 	}
 
-	// Initialize the database
-	db, err := initializeDB()
-	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
-	}
-	defer db.Close()
+	ctx := c.Request.Context()
 
 	// Check if the plate is already in the database
-	if checkPlateAlreadyInDB(db, newVisitor.Plate) {
+	exists, err := a.repo.Exists(ctx, newVisitor.Plate)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+	if exists {
 		c.JSON(http.StatusConflict, gin.H{"message": "Plate already in database"})
 		return
 	}
 
 	// Add the new visitor to the database
-	if addNewVisitorToDB(newVisitor); err != nil {
+	if err := a.repo.Insert(ctx, newVisitor); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
+	logger.Info().Str("plate", newVisitor.Plate).Str("name", newVisitor.Name).Msg("Visitor added to the database")
 	c.JSON(http.StatusCreated, newVisitor)
 }
 
-func removeVisitor(c *gin.Context) {
+func (a *visitorAPI) removeVisitor(c *gin.Context) {
 	// Get the plate from the URL
 	// Example: /visitors/ABC-123
 	plate := c.Param("plate")
+	ctx := c.Request.Context()
 
-	db, err := initializeDB()
+	// Check is in the DB
+	exists, err := a.repo.Exists(ctx, plate)
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
 	}
-	defer db.Close()
-
-	// Check is in the DB
-	if !checkPlateAlreadyInDB(db, plate) {
+	if !exists {
 		c.JSON(http.StatusConflict, gin.H{"message": "Plate is not found in the database"})
-		log.Printf("INFO: Tried to delete plate %s but it is not found in the database", plate)
+		logger.Info().Str("plate", plate).Msg("Tried to delete plate but it is not found in the database")
 		return
 	}
 
-	query := `DELETE FROM visitors
-		WHERE plate = '` + plate + `'`
-	_, err = db.Query(query)
-	if err != nil {
+	if err := a.repo.Delete(ctx, plate); err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
 		return
 	}
 	c.JSON(http.StatusOK, gin.H{"message": "Plate removed"})
-	log.Printf("INFO: Plate %s removed from the database", plate)
+	logger.Info().Str("plate", plate).Msg("Plate removed from the database")
 }
 
-// Function that connect to DB and adds a new visitor
-// Returns an error if something went wrong
-// Not checking if users exists, this should be done before calling this function
-//
-// Example:
+// This function gets called by the API
+// GET to /visitors/history?plate=ABC-123
 //
-//	addNewVisitorToDB(visitors{Name: "Jordy", Plate: "ABC-123"})
-func addNewVisitorToDB(visitor visitors) {
-	db, err := initializeDB()
-	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
-	}
-	defer db.Close()
+// Returns visitors that were swept out of the visitors table because their
+// booking window or entry allowance ran out. Omit the plate query parameter
+// to return the full history.
+func (a *visitorAPI) getVisitorHistory(c *gin.Context) {
+	plate := c.Query("plate")
 
-	query := `INSERT INTO visitors (name, plate)
-		VALUES (?, ?)`
-	_, err = db.Query(query, visitor.Name, visitor.Plate)
-	if err != nil {
-		log.Fatalf("ERROR: Cannot insert new visitor: %v", err)
-	}
-	log.Printf("INFO: Visitor %s with plate %s added to the database", visitor.Name, visitor.Plate)
-}
-
-// Function to get all visitors from the database
-// Returns a slice of visitors
-//
-// Example response:
-//
-//	[
-//		{
-//			"name": "Jordy",
-//			"plate": "ABC-123"
-//		},
-//		{
-//			"name": "Piet",
-//			"plate": "DEF-456"
-//		}
-//	]
-func getVisitorsFromDB(EnteredPlate string) []visitors {
-	db, err := initializeDB()
+	history, err := a.repo.History(c.Request.Context(), plate)
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
-	}
-	defer db.Close()
-
-	// If the plate is given, get the visitor with the given plate
-	rows := &sql.Rows{}
-
-	if EnteredPlate == "" {
-		query := `SELECT name, plate
-			FROM visitors`
-		rows, _ = db.Query(query)
-	} else {
-		query := `SELECT name, plate
-			FROM visitors
-			WHERE plate = ?`
-		rows, _ = db.Query(query, EnteredPlate)
-
-	}
-
-	var name, plate string
-	var visitorList []visitors
-	for rows.Next() {
-		err := rows.Scan(&name, &plate)
-		if err != nil {
-			log.Fatalf("ERROR: Cannot scan the row: %v", err)
-		}
-		visitor := visitors{
-			Name:  name,
-			Plate: plate,
-		}
-		visitorList = append(visitorList, visitor)
-
-		// Check if the list is empty
-		if len(visitorList) == 0 {
-			return nil
-		}
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
 	}
-	return visitorList
+	c.JSON(http.StatusOK, history)
 }
 
 // Create a struct to store the configuration data
 //
 // Example:
 //
-//	global:
-//		debug: true
+//	logging:
+//		level: "info"
 //	database:
 //		user: "username"
 //		password: "your password"
@@ -289,9 +313,40 @@ type Config struct {
 		Port     string `yaml:"port"`
 		Database string `yaml:"database"`
 	} `yaml:"database"`
-	Global struct {
-		Debug bool `yaml:"debug"`
-	} `yaml:"global"`
+	// Logging controls the minimum level written to trace.log and stdout:
+	// "debug", "info", "warn", "error" or "fatal".
+	Logging struct {
+		Level string `yaml:"level"`
+	} `yaml:"logging"`
+	// OCR holds the configuration for plate recognition. Endpoint, APIKey
+	// and Retries are only used by HTTPOCRProvider; MinConfidence and
+	// TimeoutSec apply to every provider, including the local openalpr one.
+	OCR struct {
+		Endpoint      string  `yaml:"endpoint"`
+		APIKey        string  `yaml:"api_key"`
+		MinConfidence float64 `yaml:"min_confidence"`
+		TimeoutSec    int     `yaml:"timeout_seconds"`
+		Retries       int     `yaml:"retries"`
+	} `yaml:"ocr"`
+	// Auth holds the secret used to sign and verify JWT sessions.
+	Auth struct {
+		Secret string `yaml:"secret"`
+	} `yaml:"auth"`
+	// MQTT holds the connection details for the barrier hardware broker.
+	MQTT struct {
+		Host     string `yaml:"host"`
+		Port     int    `yaml:"port"`
+		TLS      bool   `yaml:"tls"`
+		ClientID string `yaml:"client_id"`
+		Username string `yaml:"username"`
+		Password string `yaml:"password"`
+		Topics   struct {
+			ScanAllowed    string `yaml:"scan_allowed"`
+			ScanDenied     string `yaml:"scan_denied"`
+			BarrierStatus  string `yaml:"barrier_status"`
+			BarrierCommand string `yaml:"barrier_command"`
+		} `yaml:"topics"`
+	} `yaml:"mqtt"`
 }
 
 // Create a new variable of type Config
@@ -305,19 +360,19 @@ func readYaml() {
 	// If the file does not exist, create a new file
 	if _, err := os.Stat("config.yaml"); os.IsNotExist(err) {
 		writeYaml()
-		log.Fatalf("Config file does not exist. A new config file has been created. Please fill in the configuration data and restart the program.")
+		panic("Config file does not exist. A new config file has been created. Please fill in the configuration data and restart the program.")
 	}
 
 	// Read the yaml file and store it in the 'yamlFile' variable
 	yamlFile, err := os.ReadFile("config.yaml")
 	if err != nil {
-		log.Fatalf("Cannot read the YAML-file: %v", err)
+		panic("Cannot read the YAML-file: " + err.Error())
 	}
 
 	// Decode the YAML data and store it in the 'config' variable
 	err = yaml.Unmarshal(yamlFile, &config)
 	if err != nil {
-		log.Fatalf("Cannot decode YAML-file: %v", err)
+		panic("Cannot decode YAML-file: " + err.Error())
 	}
 }
 
@@ -337,10 +392,60 @@ func writeYaml() {
 			Port:     "",
 			Database: "",
 		},
-		Global: struct {
-			Debug bool `yaml:"debug"`
+		Logging: struct {
+			Level string `yaml:"level"`
+		}{
+			Level: "info",
+		},
+		OCR: struct {
+			Endpoint      string  `yaml:"endpoint"`
+			APIKey        string  `yaml:"api_key"`
+			MinConfidence float64 `yaml:"min_confidence"`
+			TimeoutSec    int     `yaml:"timeout_seconds"`
+			Retries       int     `yaml:"retries"`
 		}{
-			Debug: false,
+			Endpoint:      "",
+			APIKey:        "",
+			MinConfidence: 0.75,
+			TimeoutSec:    5,
+			Retries:       2,
+		},
+		Auth: struct {
+			Secret string `yaml:"secret"`
+		}{
+			Secret: "",
+		},
+		MQTT: struct {
+			Host     string `yaml:"host"`
+			Port     int    `yaml:"port"`
+			TLS      bool   `yaml:"tls"`
+			ClientID string `yaml:"client_id"`
+			Username string `yaml:"username"`
+			Password string `yaml:"password"`
+			Topics   struct {
+				ScanAllowed    string `yaml:"scan_allowed"`
+				ScanDenied     string `yaml:"scan_denied"`
+				BarrierStatus  string `yaml:"barrier_status"`
+				BarrierCommand string `yaml:"barrier_command"`
+			} `yaml:"topics"`
+		}{
+			Host:     "",
+			Port:     1883,
+			TLS:      false,
+			ClientID: "gatekeeper",
+			Username: "",
+			Password: "",
+			Topics: struct {
+				ScanAllowed    string `yaml:"scan_allowed"`
+				ScanDenied     string `yaml:"scan_denied"`
+				BarrierStatus  string `yaml:"barrier_status"`
+				BarrierCommand string `yaml:"barrier_command"`
+			}{
+				ScanAllowed:    "gatekeeper/scan/allowed",
+				ScanDenied:     "gatekeeper/scan/denied",
+				BarrierStatus:  "gatekeeper/barrier/status",
+				BarrierCommand: "gatekeeper/barrier/command",
+			},
 		},
 	}
 
@@ -348,13 +453,13 @@ func writeYaml() {
 	// Converting the data to a YAML format
 	yamlData, err := yaml.Marshal(&config)
 	if err != nil {
-		log.Fatalf("Cannot marshal data: %v", err)
+		panic("Cannot marshal data: " + err.Error())
 	}
 
 	// Write the data to the file
 	err = os.WriteFile("config.yaml", yamlData, 0644)
 	if err != nil {
-		log.Fatalf("Cannot write to file: %v", err)
+		panic("Cannot write to file: " + err.Error())
 	}
 
 	fmt.Println("Config file created successfully.")
@@ -368,7 +473,7 @@ func initializeDB() (*sql.DB, error) {
 
 // First message that will be shown to the user
 func startProgramMessage() {
-	if config.Global.Debug {
+	if config.Logging.Level == "debug" {
 		fmt.Println("!! Debug mode is enabled !!")
 	}
 	fmt.Println("----------------------")
@@ -413,7 +518,12 @@ func startManagementMessage() {
 	fmt.Scanln(&option)
 	switch option {
 	case 1:
-		showAllPlates()
+		db, err := initializeDB()
+		if err != nil {
+			logger.Fatal().Err(err).Msg("Cannot connect to the database")
+		}
+		defer db.Close()
+		showAllPlates(NewVisitorRepo(db))
 	case 2:
 		addNewPlate()
 	case 3:
@@ -425,31 +535,25 @@ func startManagementMessage() {
 	}
 }
 
-// Function to check if the scanned plate is in the database
-// Returns a boolean value
-// If the plate is in the database, it will return true
-// If the plate is not in the database, it will return false
-func checkScannedPlateInDB(db *sql.DB, givenPlate string) bool {
+// Function to check if the scanned plate is in the database and currently
+// allowed through the gate.
+// Returns true if the plate is in the database, within its
+// valid_from/valid_until window (if any), and still has entries left (if
+// max_entries is set). A successful check atomically consumes one entry.
+//
+// DB errors are returned rather than being fatal: this is called from the
+// HTTP /visitors/scan handler, where a transient query failure must only
+// fail that one request, not bring down the whole process.
+func checkScannedPlateInDB(repo *VisitorRepo, givenPlate string) (bool, error) {
 	debug("Entered plate: " + givenPlate)
-	query := `SELECT name, plate 
-		FROM visitors
-		WHERE plate ` + " = '" + givenPlate + "'"
-	rows, _ := db.Query(query)
-	var name, plate string
-
-	// Loop through the rows
-	// check if the given plate is in the database
-	for rows.Next() {
-		err := rows.Scan(&name, &plate)
-		if err != nil {
-			log.Fatalf("ERROR: Cannot scan the row: %v", err)
-		}
-		debug("Returned data from query: " + name + " " + plate)
-		if plate == givenPlate {
-			return true
-		}
+	allowed, reason, err := repo.CheckAndConsume(context.Background(), givenPlate)
+	if err != nil {
+		return false, err
+	}
+	if !allowed {
+		logger.Info().Str("plate", givenPlate).Str("reason", reason).Msg("Kenteken geweigerd")
 	}
-	return false
+	return allowed, nil
 }
 
 func pressKeyToContinue() {
@@ -489,61 +593,55 @@ func scanPlate() {
 
 	db, err := initializeDB()
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
+		logger.Fatal().Err(err).Msg("Cannot connect to the database")
 	}
 	defer db.Close()
-	if !checkScannedPlateInDB(db, plate) {
+	repo := NewVisitorRepo(db)
+	allowed, err := checkScannedPlateInDB(repo, plate)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot check plate")
+	}
+
+	decision, name := "denied", ""
+	if allowed {
+		decision = "allowed"
+		name = getLinkedNameOfPlate(repo, plate)
+	}
+	scansTotal.WithLabelValues(decision).Inc()
+	if broker != nil {
+		broker.PublishScanDecision(plate, name, decision, "cli-scan")
+	}
+
+	if !allowed {
 		fmt.Println("Kenteken niet toegestaan")
-		log.Printf("INFO: Kenteken %s is niet toegelaten", plate)
+		logger.Info().Str("plate", plate).Msg("Kenteken is niet toegelaten")
 		return
 	}
-	log.Printf("INFO: Kenteken %s is doorgelaten", plate)
+	logger.Info().Str("plate", plate).Msg("Kenteken is doorgelaten")
 	firstMessage()
 }
 
 // Function to get the linked name to the given plate
 // Returns a string with the linked name
-func getLinkedNameOfPlate(plate string) string {
-	db, err := initializeDB()
+func getLinkedNameOfPlate(repo *VisitorRepo, plate string) string {
+	visitor, err := repo.Get(context.Background(), plate)
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
-	}
-	defer db.Close()
-
-	query := `SELECT name, plate
-		FROM visitors
-		WHERE plate = '` + plate + `'`
-	rows, _ := db.Query(query)
-	var name, plateDB string
-	for rows.Next() {
-		err := rows.Scan(&name, &plateDB)
-		if err != nil {
-			log.Fatalf("ERROR: Cannot scan the row: %v", err)
-		}
+		return ""
 	}
-	return name
+	return visitor.Name
 }
 
 // Function to show all plates in the database
 // This function will print all plates
-func showAllPlates() {
-	db, err := initializeDB()
+func showAllPlates(repo *VisitorRepo) {
+	list, err := repo.List(context.Background())
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
+		logger.Fatal().Err(err).Msg("Cannot list visitors")
 	}
-	defer db.Close()
 
-	query := `SELECT name, plate
-		FROM visitors`
-	rows, _ := db.Query(query)
-	var name, plate string
 	fmt.Println("Lijst kentekens:")
-	for rows.Next() {
-		err := rows.Scan(&name, &plate)
-		if err != nil {
-			log.Fatalf("ERROR: Cannot scan the row: %v", err)
-		}
-		fmt.Println(name + " " + plate)
+	for _, visitor := range list {
+		fmt.Println(visitor.Name + " " + visitor.Plate)
 	}
 	pressKeyToContinue()
 	startManagementMessage()
@@ -553,27 +651,15 @@ func showAllPlates() {
 // Returns a boolean value
 // false = not in the database
 // true = in the database
-func checkPlateAlreadyInDB(db *sql.DB, givenPlate string) bool {
-	debug("Entered plate: " + givenPlate)
-	query := `SELECT name, plate
-		FROM visitors
-		WHERE plate ` + " = '" + givenPlate + "'"
-	rows, _ := db.Query(query)
-	var name, plate string
-
-	// Loop through the rows
-	// check if the given plate is in the database
-	for rows.Next() {
-		err := rows.Scan(&name, &plate)
-		if err != nil {
-			log.Fatalf("ERROR: Cannot scan the row: %v", err)
-		}
-		debug("Returned data from query: " + name + " " + plate)
-		if plate == givenPlate {
-			return true
-		}
+//
+// This is a plain existence check, not a gate decision: unlike
+// checkScannedPlateInDB it must not consume one of the plate's entries.
+func checkPlateAlreadyInDB(repo *VisitorRepo, givenPlate string) bool {
+	exists, err := repo.Exists(context.Background(), givenPlate)
+	if err != nil {
+		logger.Fatal().Err(err).Msg("Cannot check plate")
 	}
-	return false
+	return exists
 }
 
 // Function to add a new plate to the database
@@ -581,9 +667,10 @@ func checkPlateAlreadyInDB(db *sql.DB, givenPlate string) bool {
 func addNewPlate() {
 	db, err := initializeDB()
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
+		logger.Fatal().Err(err).Msg("Cannot connect to the database")
 	}
 	defer db.Close()
+	repo := NewVisitorRepo(db)
 
 	// Ask for the name and plate number
 	// using scanner to accept spaces
@@ -610,8 +697,8 @@ func addNewPlate() {
 
 	// Check if the plate is already in the database
 	// If linked to a name ask to overwrite
-	if checkPlateAlreadyInDB(db, plate) {
-		fmt.Printf("Kenteken %s bestaat al, deze is van %s, wil je deze overschrijven? (j/N): ", plate, getLinkedNameOfPlate(plate))
+	if checkPlateAlreadyInDB(repo, plate) {
+		fmt.Printf("Kenteken %s bestaat al, deze is van %s, wil je deze overschrijven? (j/N): ", plate, getLinkedNameOfPlate(repo, plate))
 		var correct string
 		fmt.Scanln(&correct)
 		if correct != "j" {
@@ -621,13 +708,8 @@ func addNewPlate() {
 		}
 
 		// Replace the name with given name
-		query := `UPDATE visitors
-				SET name = '` + name + `'
-				WHERE plate = '` + plate + `'`
-		// Execute the query
-		_, err = db.Query(query)
-		if err != nil {
-			log.Printf("ERROR: Cannot update plate: %v", err)
+		if err := repo.Update(context.Background(), visitors{Name: name, Plate: plate}); err != nil {
+			logger.Error().Err(err).Msg("Cannot update plate")
 			fmt.Print("Opnieuw proberen? (j/N): ")
 			var correct string
 			fmt.Scanln(&correct)
@@ -638,18 +720,15 @@ func addNewPlate() {
 			addNewPlate()
 		}
 
-		log.Printf("INFO: Kenteken %s staat nu op naam van %s", plate, name)
+		logger.Info().Str("plate", plate).Str("name", name).Msg("Kenteken staat nu op nieuwe naam")
 		time.Sleep(3 * time.Second)
 		startManagementMessage()
 	}
 
-	query := `INSERT INTO visitors (name, plate)
-		VALUES ('` + name + `', '` + plate + `')`
-	_, err = db.Query(query)
-	if err != nil {
-		log.Fatalf("ERROR: Cannot insert new plate: %v", err)
+	if err := repo.Insert(context.Background(), visitors{Name: name, Plate: plate}); err != nil {
+		logger.Fatal().Err(err).Msg("Cannot insert new plate")
 	}
-	log.Printf("INFO: Kenteken %s toegevoegd onder naam van %s", plate, name)
+	logger.Info().Str("plate", plate).Str("name", name).Msg("Kenteken toegevoegd")
 	time.Sleep(3 * time.Second)
 	startManagementMessage()
 }
@@ -657,15 +736,16 @@ func addNewPlate() {
 func removePlate() {
 	db, err := initializeDB()
 	if err != nil {
-		log.Fatalf("ERROR: Cannot connect to the database: %v", err)
+		logger.Fatal().Err(err).Msg("Cannot connect to the database")
 	}
 	defer db.Close()
+	repo := NewVisitorRepo(db)
 
 	fmt.Print("Kenteken: ")
 	var plate string
 	fmt.Scanln(&plate)
 	fmt.Println("Ingevoerd kenteken: " + plate)
-	fmt.Println("Dit kenteken hoort bij: " + getLinkedNameOfPlate(plate))
+	fmt.Println("Dit kenteken hoort bij: " + getLinkedNameOfPlate(repo, plate))
 	fmt.Print("Is dit correct? (j/N): ")
 	var correct string
 	fmt.Scanln(&correct)
@@ -673,13 +753,10 @@ func removePlate() {
 		removePlate()
 	}
 
-	query := `DELETE FROM visitors
-		WHERE plate = '` + plate + `'`
-	_, err = db.Query(query)
-	if err != nil {
-		log.Fatalf("ERROR: Cannot remove plate: %v", err)
+	if err := repo.Delete(context.Background(), plate); err != nil {
+		logger.Fatal().Err(err).Msg("Cannot remove plate")
 	}
-	log.Printf("INFO: Kenteken %s verwijderd", plate)
+	logger.Info().Str("plate", plate).Msg("Kenteken verwijderd")
 	time.Sleep(3 * time.Second)
 	startManagementMessage()
 }
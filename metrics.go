@@ -0,0 +1,65 @@
+//
+// Description: Prometheus metrics for the HTTP API, gate decisions and
+// database queries
+// Sources:
+// https://pkg.go.dev/github.com/prometheus/client_golang/prometheus
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"strconv"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promauto"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+)
+
+var (
+	scansTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gatekeeper_scans_total",
+		Help: "Total number of gate scans, by decision.",
+	}, []string{"result"})
+
+	httpRequestsTotal = promauto.NewCounterVec(prometheus.CounterOpts{
+		Name: "gatekeeper_http_requests_total",
+		Help: "Total number of HTTP requests handled, by route, method and status.",
+	}, []string{"route", "method", "status"})
+
+	dbQueryDuration = promauto.NewHistogramVec(prometheus.HistogramOpts{
+		Name: "gatekeeper_db_query_duration_seconds",
+		Help: "Duration of database queries, by operation.",
+	}, []string{"op"})
+
+	visitorsCurrent = promauto.NewGauge(prometheus.GaugeOpts{
+		Name: "gatekeeper_visitors_current",
+		Help: "Current number of visitors in the database.",
+	})
+)
+
+// metricsMiddleware records gatekeeper_http_requests_total for every route
+// registered on the router.
+func metricsMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		c.Next()
+
+		route := c.FullPath()
+		if route == "" {
+			route = "unmatched"
+		}
+		httpRequestsTotal.WithLabelValues(route, c.Request.Method, strconv.Itoa(c.Writer.Status())).Inc()
+	}
+}
+
+// observeQueryDuration records how long a database operation took under
+// gatekeeper_db_query_duration_seconds{op=...}.
+func observeQueryDuration(op string, start time.Time) {
+	dbQueryDuration.WithLabelValues(op).Observe(time.Since(start).Seconds())
+}
+
+// metricsHandler exposes the registered collectors on GET /metrics.
+var metricsHandler = gin.WrapH(promhttp.Handler())
@@ -0,0 +1,184 @@
+//
+// Description: Pluggable OCR/ALPR backends used by the /visitors/scan endpoint
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"sort"
+	"time"
+)
+
+// PlateCandidate represents a single plate reading returned by an OCR backend.
+// BBox is the bounding box of the plate in the source image, in pixels,
+// as [x, y, width, height].
+type PlateCandidate struct {
+	Plate      string  `json:"plate"`
+	Confidence float64 `json:"confidence"`
+	BBox       [4]int  `json:"bbox"`
+}
+
+// OCRProvider is implemented by anything that can turn a raw image into a
+// list of plate candidates, ordered by confidence (highest first).
+type OCRProvider interface {
+	Recognize(ctx context.Context, imageBytes []byte) ([]PlateCandidate, error)
+}
+
+// LocalALPRProvider recognizes plates by shelling out to a locally installed
+// openalpr binary. Useful when the gate controller has no internet access.
+// The binary is called with alpr's "-j -" arguments and its output is parsed
+// as alpr JSON; tesseract, which takes other arguments and prints plain
+// text, is not supported.
+type LocalALPRProvider struct {
+	// Binary is the executable to invoke; defaults to "alpr".
+	Binary string
+}
+
+// alprResult mirrors the subset of `alpr -j` JSON output we care about.
+type alprResult struct {
+	Results []struct {
+		Plate       string  `json:"plate"`
+		Confidence  float64 `json:"confidence"`
+		Coordinates []struct {
+			X int `json:"x"`
+			Y int `json:"y"`
+		} `json:"coordinates"`
+	} `json:"results"`
+}
+
+// Recognize pipes imageBytes to the configured binary on stdin and parses
+// its JSON output into plate candidates, highest confidence first.
+func (p LocalALPRProvider) Recognize(ctx context.Context, imageBytes []byte) ([]PlateCandidate, error) {
+	binary := p.Binary
+	if binary == "" {
+		binary = "alpr"
+	}
+
+	cmd := exec.CommandContext(ctx, binary, "-j", "-")
+	cmd.Stdin = bytes.NewReader(imageBytes)
+
+	var stdout bytes.Buffer
+	cmd.Stdout = &stdout
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("cannot run %s: %w", binary, err)
+	}
+
+	var parsed alprResult
+	if err := json.Unmarshal(stdout.Bytes(), &parsed); err != nil {
+		return nil, fmt.Errorf("cannot parse %s output: %w", binary, err)
+	}
+
+	candidates := make([]PlateCandidate, 0, len(parsed.Results))
+	for _, r := range parsed.Results {
+		var bbox [4]int
+		if len(r.Coordinates) >= 3 {
+			// alpr's coordinates walk the plate's four corners around the
+			// perimeter, so index 0 and 1 are adjacent (same edge); the
+			// diagonal pair needed for width/height is 0 and 2.
+			x1, y1 := r.Coordinates[0].X, r.Coordinates[0].Y
+			x2, y2 := r.Coordinates[2].X, r.Coordinates[2].Y
+			bbox = [4]int{x1, y1, abs(x2 - x1), abs(y2 - y1)}
+		}
+		candidates = append(candidates, PlateCandidate{
+			Plate:      r.Plate,
+			Confidence: r.Confidence,
+			BBox:       bbox,
+		})
+	}
+	sortByConfidence(candidates)
+	return candidates, nil
+}
+
+// HTTPOCRProvider recognizes plates by posting the image to a remote
+// recognition service configured under the `ocr:` section of the YAML Config.
+type HTTPOCRProvider struct {
+	Endpoint string
+	APIKey   string
+	Timeout  time.Duration
+	Retries  int
+}
+
+// httpOCRResponse is the expected JSON shape of the remote service's response.
+type httpOCRResponse struct {
+	Candidates []PlateCandidate `json:"candidates"`
+}
+
+// Recognize posts imageBytes to the configured endpoint, retrying up to
+// Retries times when an attempt fails: a transport error, a status other
+// than 200 OK, or a response body that cannot be decoded.
+func (p HTTPOCRProvider) Recognize(ctx context.Context, imageBytes []byte) ([]PlateCandidate, error) {
+	client := &http.Client{Timeout: p.Timeout}
+
+	var lastErr error
+	for attempt := 0; attempt <= p.Retries; attempt++ {
+		candidates, err := p.doRequest(ctx, client, imageBytes)
+		if err == nil {
+			return candidates, nil
+		}
+		lastErr = err
+	}
+	return nil, lastErr
+}
+
+// doRequest performs a single attempt at posting imageBytes to the remote
+// recognition service and decoding its response.
+func (p HTTPOCRProvider) doRequest(ctx context.Context, client *http.Client, imageBytes []byte) ([]PlateCandidate, error) {
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.Endpoint, bytes.NewReader(imageBytes))
+	if err != nil {
+		return nil, fmt.Errorf("cannot build OCR request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	req.Header.Set("Authorization", "Bearer "+p.APIKey)
+
+	resp, err := client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("OCR service returned status %s", resp.Status)
+	}
+
+	var parsed httpOCRResponse
+	if err := json.NewDecoder(resp.Body).Decode(&parsed); err != nil {
+		return nil, fmt.Errorf("cannot decode OCR response: %w", err)
+	}
+	sortByConfidence(parsed.Candidates)
+	return parsed.Candidates, nil
+}
+
+// sortByConfidence orders candidates by confidence, highest first.
+func sortByConfidence(candidates []PlateCandidate) {
+	sort.SliceStable(candidates, func(i, j int) bool {
+		return candidates[i].Confidence > candidates[j].Confidence
+	})
+}
+
+// abs returns the absolute value of n.
+func abs(n int) int {
+	if n < 0 {
+		return -n
+	}
+	return n
+}
+
+// minConfidenceFilter drops candidates below minConfidence and returns the
+// remaining ones, still ordered by confidence.
+func minConfidenceFilter(candidates []PlateCandidate, minConfidence float64) []PlateCandidate {
+	filtered := make([]PlateCandidate, 0, len(candidates))
+	for _, c := range candidates {
+		if c.Confidence >= minConfidence {
+			filtered = append(filtered, c)
+		}
+	}
+	return filtered
+}
@@ -0,0 +1,357 @@
+//
+// Description: VisitorRepo wraps *sql.DB behind a small set of parameterized
+// queries, so the rest of the codebase never builds SQL by concatenating
+// user input
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"context"
+	"database/sql"
+	"strings"
+	"time"
+)
+
+// VisitorRepo is the single place allowed to query the visitors table.
+// All methods use `?` placeholders and the *Context variants of database/sql
+// so callers can cancel a slow query instead of blocking forever.
+type VisitorRepo struct {
+	db *sql.DB
+}
+
+// NewVisitorRepo wraps db in a VisitorRepo. db is expected to be opened once
+// at startup and shared across requests.
+func NewVisitorRepo(db *sql.DB) *VisitorRepo {
+	return &VisitorRepo{db: db}
+}
+
+// visitorAPI holds the dependencies shared by the /visitors and
+// /visitors/scan handlers, built once in main() instead of every handler
+// opening its own database connection.
+type visitorAPI struct {
+	repo *VisitorRepo
+}
+
+// rowScanner is satisfied by both *sql.Row and *sql.Rows, so scanVisitor can
+// back List and Get with a single Scan call.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
+
+// scanVisitor reads a single visitors row, including its nullable booking
+// window and entry-count columns.
+func scanVisitor(row rowScanner) (visitors, error) {
+	var v visitors
+	var validFrom, validUntil sql.NullTime
+	var maxEntries, entriesUsed sql.NullInt64
+	if err := row.Scan(&v.Name, &v.Plate, &validFrom, &validUntil, &maxEntries, &entriesUsed); err != nil {
+		return visitors{}, err
+	}
+	if validFrom.Valid {
+		v.ValidFrom = &validFrom.Time
+	}
+	if validUntil.Valid {
+		v.ValidUntil = &validUntil.Time
+	}
+	if maxEntries.Valid {
+		n := int(maxEntries.Int64)
+		v.MaxEntries = &n
+	}
+	if entriesUsed.Valid {
+		n := int(entriesUsed.Int64)
+		v.EntriesUsed = &n
+	}
+	return v, nil
+}
+
+// List returns every visitor in the database.
+func (r *VisitorRepo) List(ctx context.Context) ([]visitors, error) {
+	defer observeQueryDuration("list", time.Now())
+
+	query := `SELECT name, plate, valid_from, valid_until, max_entries, entries_used FROM visitors`
+	rows, err := r.db.QueryContext(ctx, query)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []visitors
+	for rows.Next() {
+		v, err := scanVisitor(rows)
+		if err != nil {
+			return nil, err
+		}
+		list = append(list, v)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+
+	visitorsCurrent.Set(float64(len(list)))
+	return list, nil
+}
+
+// Get returns the visitor with the given plate, or sql.ErrNoRows if none
+// exists.
+func (r *VisitorRepo) Get(ctx context.Context, plate string) (visitors, error) {
+	query := `SELECT name, plate, valid_from, valid_until, max_entries, entries_used
+		FROM visitors WHERE plate = ?`
+	row := r.db.QueryRowContext(ctx, query, plate)
+	return scanVisitor(row)
+}
+
+// Exists reports whether a visitor with the given plate is already in the
+// database.
+func (r *VisitorRepo) Exists(ctx context.Context, plate string) (bool, error) {
+	defer observeQueryDuration("exists", time.Now())
+
+	_, err := r.Get(ctx, plate)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}
+
+// Insert adds a new visitor to the database. If MaxEntries is set and
+// EntriesUsed is not, EntriesUsed is initialized to MaxEntries so the first
+// CheckAndConsume call has an allowance to count down from.
+func (r *VisitorRepo) Insert(ctx context.Context, v visitors) error {
+	defer observeQueryDuration("insert", time.Now())
+
+	entriesUsed := v.EntriesUsed
+	if v.MaxEntries != nil && entriesUsed == nil {
+		entriesUsed = v.MaxEntries
+	}
+
+	query := `INSERT INTO visitors (name, plate, valid_from, valid_until, max_entries, entries_used)
+		VALUES (?, ?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, query, v.Name, v.Plate, v.ValidFrom, v.ValidUntil, v.MaxEntries, entriesUsed)
+	return err
+}
+
+// Update overwrites the name linked to an existing plate.
+func (r *VisitorRepo) Update(ctx context.Context, v visitors) error {
+	query := `UPDATE visitors SET name = ? WHERE plate = ?`
+	_, err := r.db.ExecContext(ctx, query, v.Name, v.Plate)
+	return err
+}
+
+// Delete removes the visitor with the given plate from the database.
+func (r *VisitorRepo) Delete(ctx context.Context, plate string) error {
+	query := `DELETE FROM visitors WHERE plate = ?`
+	_, err := r.db.ExecContext(ctx, query, plate)
+	return err
+}
+
+// RecordScan inserts a row into the scans table so operators can audit
+// false positives, reusing the repo's shared db connection instead of
+// opening a fresh one per scan.
+func (r *VisitorRepo) RecordScan(ctx context.Context, attempt scanAttempt) error {
+	defer observeQueryDuration("record_scan", time.Now())
+
+	query := `INSERT INTO scans (timestamp, plate, confidence, decision, image_path)
+		VALUES (?, ?, ?, ?, ?)`
+	_, err := r.db.ExecContext(ctx, query, time.Now(), attempt.Plate, attempt.Confidence, attempt.Decision, attempt.ImagePath)
+	return err
+}
+
+// InsertBarrierEvent records a status message received from the physical
+// barrier controller into the barrier_events table, reusing the repo's
+// shared db connection instead of opening a fresh one per MQTT message.
+func (r *VisitorRepo) InsertBarrierEvent(ctx context.Context, status barrierStatusPayload) error {
+	defer observeQueryDuration("insert_barrier_event", time.Now())
+
+	query := `INSERT INTO barrier_events (status, timestamp) VALUES (?, ?)`
+	_, err := r.db.ExecContext(ctx, query, status.Status, status.Timestamp)
+	return err
+}
+
+// CheckAndConsume reports whether plate currently has gate access, taking
+// into account its valid_from/valid_until booking window and remaining
+// max_entries, and atomically decrements entries_used when access is
+// granted. The single guarded UPDATE is what makes the check-and-decrement
+// atomic; reason is only for logging why access was denied.
+func (r *VisitorRepo) CheckAndConsume(ctx context.Context, plate string) (allowed bool, reason string, err error) {
+	defer observeQueryDuration("check_and_consume", time.Now())
+
+	update := `UPDATE visitors
+		SET entries_used = entries_used - 1
+		WHERE plate = ?
+			AND (valid_from IS NULL OR valid_from <= NOW())
+			AND (valid_until IS NULL OR valid_until >= NOW())
+			AND (max_entries IS NULL OR entries_used > 0)`
+	res, err := r.db.ExecContext(ctx, update, plate)
+	if err != nil {
+		return false, "", err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return false, "", err
+	}
+	if n > 0 {
+		return true, "ok", nil
+	}
+
+	reason, err = r.denialReason(ctx, plate)
+	return false, reason, err
+}
+
+// denialReason looks up why CheckAndConsume's update matched no rows, so
+// checkScannedPlateInDB can log a specific reason instead of a generic denial.
+func (r *VisitorRepo) denialReason(ctx context.Context, plate string) (string, error) {
+	query := `SELECT valid_from, valid_until, max_entries, entries_used
+		FROM visitors WHERE plate = ?`
+	row := r.db.QueryRowContext(ctx, query, plate)
+
+	var validFrom, validUntil sql.NullTime
+	var maxEntries, entriesUsed sql.NullInt64
+	if err := row.Scan(&validFrom, &validUntil, &maxEntries, &entriesUsed); err != nil {
+		if err == sql.ErrNoRows {
+			return "not_found", nil
+		}
+		return "", err
+	}
+
+	now := time.Now()
+	switch {
+	case validFrom.Valid && now.Before(validFrom.Time):
+		return "before_valid_from", nil
+	case validUntil.Valid && now.After(validUntil.Time):
+		return "after_valid_until", nil
+	case maxEntries.Valid && entriesUsed.Valid && entriesUsed.Int64 <= 0:
+		return "entries_exhausted", nil
+	default:
+		return "unknown", nil
+	}
+}
+
+// visitorHistory is a visitors row that was moved out of the visitors table
+// by SweepExpired, together with the time it was archived.
+type visitorHistory struct {
+	visitors
+	ArchivedAt time.Time `json:"archived_at"`
+}
+
+// History returns archived visitors, most recently archived first. If plate
+// is empty, the full history is returned.
+func (r *VisitorRepo) History(ctx context.Context, plate string) ([]visitorHistory, error) {
+	query := `SELECT name, plate, valid_from, valid_until, max_entries, entries_used, archived_at
+		FROM visitors_history`
+	var args []interface{}
+	if plate != "" {
+		query += ` WHERE plate = ?`
+		args = append(args, plate)
+	}
+	query += ` ORDER BY archived_at DESC`
+
+	rows, err := r.db.QueryContext(ctx, query, args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var list []visitorHistory
+	for rows.Next() {
+		var h visitorHistory
+		var validFrom, validUntil sql.NullTime
+		var maxEntries, entriesUsed sql.NullInt64
+		if err := rows.Scan(&h.Name, &h.Plate, &validFrom, &validUntil, &maxEntries, &entriesUsed, &h.ArchivedAt); err != nil {
+			return nil, err
+		}
+		if validFrom.Valid {
+			h.ValidFrom = &validFrom.Time
+		}
+		if validUntil.Valid {
+			h.ValidUntil = &validUntil.Time
+		}
+		if maxEntries.Valid {
+			n := int(maxEntries.Int64)
+			h.MaxEntries = &n
+		}
+		if entriesUsed.Valid {
+			n := int(entriesUsed.Int64)
+			h.EntriesUsed = &n
+		}
+		list = append(list, h)
+	}
+	if err := rows.Err(); err != nil {
+		return nil, err
+	}
+	return list, nil
+}
+
+// SweepExpired moves every visitor whose valid_until has passed, or whose
+// entries_used has hit zero under a max_entries cap, into visitors_history.
+// It is called once a minute by the background goroutine started in main().
+//
+// The expired plates are locked with SELECT ... FOR UPDATE inside a single
+// transaction, and that exact plate list is then reused for both the
+// archiving INSERT and the DELETE. Without this, a concurrent
+// CheckAndConsume could push a row's entries_used to 0 between two
+// independently re-evaluated WHERE clauses, so the DELETE would remove a
+// row the INSERT never captured, silently losing it instead of archiving it.
+func (r *VisitorRepo) SweepExpired(ctx context.Context) (int64, error) {
+	defer observeQueryDuration("sweep_expired", time.Now())
+
+	tx, err := r.db.BeginTx(ctx, nil)
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	expired := `(valid_until IS NOT NULL AND valid_until < NOW())
+		OR (max_entries IS NOT NULL AND entries_used <= 0)`
+
+	rows, err := tx.QueryContext(ctx, `SELECT plate FROM visitors WHERE `+expired+` FOR UPDATE`)
+	if err != nil {
+		return 0, err
+	}
+	var plates []string
+	for rows.Next() {
+		var plate string
+		if err := rows.Scan(&plate); err != nil {
+			rows.Close()
+			return 0, err
+		}
+		plates = append(plates, plate)
+	}
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+	rows.Close()
+
+	if len(plates) == 0 {
+		return 0, tx.Commit()
+	}
+
+	placeholders := strings.TrimSuffix(strings.Repeat("?,", len(plates)), ",")
+	args := make([]interface{}, len(plates))
+	for i, plate := range plates {
+		args[i] = plate
+	}
+
+	insert := `INSERT INTO visitors_history (name, plate, valid_from, valid_until, max_entries, entries_used, archived_at)
+		SELECT name, plate, valid_from, valid_until, max_entries, entries_used, NOW()
+		FROM visitors WHERE plate IN (` + placeholders + `)`
+	if _, err := tx.ExecContext(ctx, insert, args...); err != nil {
+		return 0, err
+	}
+
+	del := `DELETE FROM visitors WHERE plate IN (` + placeholders + `)`
+	res, err := tx.ExecContext(ctx, del, args...)
+	if err != nil {
+		return 0, err
+	}
+	n, err := res.RowsAffected()
+	if err != nil {
+		return 0, err
+	}
+
+	return n, tx.Commit()
+}
@@ -0,0 +1,130 @@
+//
+// Description: ANPR image scan endpoint, reusing the existing gate-decision
+// logic so scans behave the same as a manually entered plate
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+// ocrProvider is the OCR backend used by scanVisitorImage. Defaults to the
+// local openalpr binary; can be swapped for HTTPOCRProvider once the `ocr:`
+// config section is filled in.
+var ocrProvider OCRProvider = LocalALPRProvider{}
+
+// This function gets called by the API
+// POST to /visitors/scan
+//
+// Accepts a multipart/form-data upload with an "image" field containing a
+// photo of the vehicle at the gate. The image is run through ocrProvider,
+// the best candidate above config.OCR.MinConfidence is looked up with
+// checkScannedPlateInDB, and the attempt is recorded in the scans table.
+func (a *visitorAPI) scanVisitorImage(c *gin.Context) {
+	file, header, err := c.Request.FormFile("image")
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"message": "Image is required"})
+		return
+	}
+	defer file.Close()
+
+	imageBytes, err := io.ReadAll(file)
+	if err != nil {
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	ctx, cancel := context.WithTimeout(c.Request.Context(), time.Duration(config.OCR.TimeoutSec)*time.Second)
+	defer cancel()
+
+	candidates, err := ocrProvider.Recognize(ctx, imageBytes)
+	if err != nil {
+		logger.Error().Err(err).Str("file", header.Filename).Msg("OCR recognition failed")
+		c.JSON(http.StatusBadGateway, gin.H{"message": "Could not recognize plate in image"})
+		return
+	}
+
+	candidates = minConfidenceFilter(candidates, config.OCR.MinConfidence)
+	if len(candidates) == 0 {
+		scansTotal.WithLabelValues("denied").Inc()
+		if broker != nil {
+			broker.PublishScanDecision("", "", "denied", "http-scan")
+		}
+		if err := a.repo.RecordScan(c.Request.Context(), scanAttempt{Plate: "", Confidence: 0, Decision: "denied", ImagePath: ""}); err != nil {
+			logger.Error().Err(err).Msg("Cannot record scan attempt")
+		}
+		c.JSON(http.StatusUnprocessableEntity, gin.H{"message": "No plate recognized with sufficient confidence"})
+		return
+	}
+
+	best := candidates[0]
+
+	allowed, err := checkScannedPlateInDB(a.repo, best.Plate)
+	if err != nil {
+		logger.Error().Err(err).Str("plate", best.Plate).Msg("Cannot check plate")
+		c.JSON(http.StatusInternalServerError, gin.H{"message": "Internal server error"})
+		return
+	}
+
+	decision, name := "denied", ""
+	if allowed {
+		decision = "allowed"
+		name = getLinkedNameOfPlate(a.repo, best.Plate)
+	}
+	scansTotal.WithLabelValues(decision).Inc()
+
+	if broker != nil {
+		broker.PublishScanDecision(best.Plate, name, decision, "http-scan")
+	}
+
+	imagePath, err := storeScanImage(header.Filename, imageBytes)
+	if err != nil {
+		logger.Error().Err(err).Msg("Cannot store scan image")
+	}
+
+	if err := a.repo.RecordScan(c.Request.Context(), scanAttempt{
+		Plate:      best.Plate,
+		Confidence: best.Confidence,
+		Decision:   decision,
+		ImagePath:  imagePath,
+	}); err != nil {
+		logger.Error().Err(err).Msg("Cannot record scan attempt")
+	}
+
+	logger.Info().Str("plate", best.Plate).Float64("confidence", best.Confidence).Str("decision", decision).Msg("Scan recognized plate")
+	c.JSON(http.StatusOK, gin.H{"plate": best.Plate, "confidence": best.Confidence, "decision": decision})
+}
+
+// scanAttempt is persisted to the scans table for every /visitors/scan
+// request that reaches a gate decision, allowing operators to audit false
+// positives later. Requests that fail earlier (no image, OCR error, database
+// error during the plate check) are not recorded.
+type scanAttempt struct {
+	Plate      string
+	Confidence float64
+	Decision   string
+	ImagePath  string
+}
+
+// storeScanImage writes the uploaded image to the local "scans" directory
+// and returns the path under which it was stored.
+func storeScanImage(filename string, imageBytes []byte) (string, error) {
+	if err := os.MkdirAll("scans", 0755); err != nil {
+		return "", err
+	}
+	path := filepath.Join("scans", time.Now().Format("20060102-150405-")+filepath.Base(filename))
+	if err := os.WriteFile(path, imageBytes, 0644); err != nil {
+		return "", err
+	}
+	return path, nil
+}
@@ -0,0 +1,75 @@
+//
+// Description: Storage helpers backing the users and revoked-token tables
+// used by auth.go
+//
+// Made by: Jordy Hoebergen
+//
+
+package main
+
+import (
+	"context"
+	"database/sql"
+)
+
+// storedCredentials holds the persisted hash and salt for a single user,
+// loaded from the users table so it can be compared against a login attempt.
+type storedCredentials struct {
+	hash []byte
+	salt []byte
+}
+
+// userRepo is the single place allowed to query the users and
+// revoked_tokens tables, mirroring VisitorRepo: built once in main() and
+// shared across requests instead of every auth handler opening its own
+// database connection.
+type userRepo struct {
+	db *sql.DB
+}
+
+// newUserRepo wraps db in a userRepo. db is expected to be opened once at
+// startup and shared across requests.
+func newUserRepo(db *sql.DB) *userRepo {
+	return &userRepo{db: db}
+}
+
+// GetCredentials looks up a user's stored password hash, salt and role by
+// username. Returns an error if the user does not exist.
+func (r *userRepo) GetCredentials(ctx context.Context, username string) (storedCredentials, string, error) {
+	query := `SELECT password_hash, salt, role
+		FROM users
+		WHERE username = ?`
+	row := r.db.QueryRowContext(ctx, query, username)
+
+	var creds storedCredentials
+	var role string
+	if err := row.Scan(&creds.hash, &creds.salt, &role); err != nil {
+		return storedCredentials{}, "", err
+	}
+	return creds, role, nil
+}
+
+// RevokeToken records rawToken in the revoked_tokens table so it is rejected
+// by RequireRole/refreshHandler even though it has not expired yet.
+func (r *userRepo) RevokeToken(ctx context.Context, rawToken string) error {
+	query := `INSERT INTO revoked_tokens (token) VALUES (?)`
+	_, err := r.db.ExecContext(ctx, query, rawToken)
+	return err
+}
+
+// IsTokenRevoked reports whether rawToken has been logged out via
+// logoutHandler.
+func (r *userRepo) IsTokenRevoked(ctx context.Context, rawToken string) (bool, error) {
+	query := `SELECT 1 FROM revoked_tokens WHERE token = ?`
+	row := r.db.QueryRowContext(ctx, query, rawToken)
+
+	var exists int
+	err := row.Scan(&exists)
+	if err == sql.ErrNoRows {
+		return false, nil
+	}
+	if err != nil {
+		return false, err
+	}
+	return true, nil
+}